@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+var checkFlag = flag.Bool("check", false, "List files that would be modified and exit 1 if any are, like gofmt -l")
+
+// checkFile reports whether fn would be modified by cleaning it, without
+// writing anything. err is non-nil only on an I/O failure.
+func checkFile(fn string, opts CleanOptions) (dirty bool, err error) {
+	if !isRegular(fn) {
+		return false, fmt.Errorf("couldn't stat %s", fn)
+	}
+	if !shouldTreatAsText(fn) {
+		return false, nil
+	}
+
+	contents, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return false, err
+	}
+	if len(contents) == 0 {
+		return false, nil
+	}
+	contents, _ = stripBOM(contents)
+
+	lines, terms, dominant := splitLinesEOL(contents)
+	newLines, trimmed, tabs, _, _ := cleanLines(lines, opts)
+	eols := countEOLChanges(terms, resolveEOL(opts.EOL, dominant))
+	dirty = trimmed > 0 || tabs > 0 || eols > 0 || len(lines) != len(newLines)
+	// cleanFile ends every line with a terminator, so a missing one on the
+	// last line is a change too.
+	if len(terms) > 0 && terms[len(terms)-1] == "" {
+		dirty = true
+	}
+	return
+}
+
+// runCheck implements -check: print every file that would be modified and
+// exit 1 if there were any, 0 if there weren't, or >1 on an I/O error.
+func runCheck(files []string) {
+	dirty := false
+	ioErr := false
+
+	for _, fn := range files {
+		opts := optionsFromFlags()
+		if *editorConfigFlag {
+			opts = applyEditorConfig(fn, opts)
+		}
+		d, err := checkFile(fn, opts)
+		if err != nil {
+			fmt.Println(err)
+			ioErr = true
+			continue
+		}
+		if d {
+			fmt.Println(fn)
+			dirty = true
+		}
+	}
+
+	switch {
+	case ioErr:
+		os.Exit(2)
+	case dirty:
+		os.Exit(1)
+	default:
+		os.Exit(0)
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import "fmt"
+
+// diffOp is one line of an LCS-based diff between two line slices.
+type diffOp struct {
+	kind byte // ' ' (unchanged), '-' (removed) or '+' (added)
+	text string
+}
+
+// diffLines computes a line-level diff between a and b using the classic
+// LCS dynamic-programming table, in the spirit of Myers' algorithm.
+func diffLines(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
+
+const diffContext = 3
+
+// formatUnifiedDiff renders ops as a `diff -u`-style patch with a/b headers
+// naming filename on both sides, grouping changes into @@ hunks separated
+// by at least 2*diffContext unchanged lines.
+func formatUnifiedDiff(filename string, ops []diffOp) string {
+	type hunk struct {
+		startA, startB int
+		ops            []diffOp
+	}
+
+	var hunks []hunk
+	var cur *hunk
+	aLine, bLine := 1, 1
+	trailingContext := 0
+
+	for idx := 0; idx < len(ops); idx++ {
+		op := ops[idx]
+		isChange := op.kind != ' '
+
+		if cur == nil {
+			if !isChange {
+				aLine++
+				bLine++
+				continue
+			}
+			// Start a new hunk, pulling in up to diffContext lines of
+			// leading context that we've already stepped past.
+			leadStart := idx
+			for k := 0; k < diffContext && leadStart > 0 && ops[leadStart-1].kind == ' '; k++ {
+				leadStart--
+			}
+			cur = &hunk{startA: aLine - (idx - leadStart), startB: bLine - (idx - leadStart)}
+			for k := leadStart; k < idx; k++ {
+				cur.ops = append(cur.ops, ops[k])
+			}
+			cur.ops = append(cur.ops, op)
+			trailingContext = 0
+		} else {
+			cur.ops = append(cur.ops, op)
+			if !isChange {
+				trailingContext++
+			} else {
+				trailingContext = 0
+			}
+		}
+
+		if op.kind != '+' {
+			aLine++
+		}
+		if op.kind != '-' {
+			bLine++
+		}
+
+		if cur != nil && trailingContext >= diffContext*2 {
+			// Trim the excess trailing context back down to diffContext.
+			excess := trailingContext - diffContext
+			cur.ops = cur.ops[:len(cur.ops)-excess]
+			hunks = append(hunks, *cur)
+			cur = nil
+			trailingContext = 0
+		}
+	}
+	if cur != nil {
+		hunks = append(hunks, *cur)
+	}
+
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	out := fmt.Sprintf("--- a/%s\n+++ b/%s\n", filename, filename)
+	for _, h := range hunks {
+		countA, countB := 0, 0
+		for _, op := range h.ops {
+			if op.kind != '+' {
+				countA++
+			}
+			if op.kind != '-' {
+				countB++
+			}
+		}
+		out += fmt.Sprintf("@@ -%d,%d +%d,%d @@\n", h.startA, countA, h.startB, countB)
+		for _, op := range h.ops {
+			out += string(op.kind) + op.text + "\n"
+		}
+	}
+	return out
+}
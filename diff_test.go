@@ -0,0 +1,109 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// reconstruct rebuilds the "b" side of a diff from its ops, so we can check
+// diffLines actually describes a transformation from a to b and not just
+// some same-length edit script.
+func reconstruct(ops []diffOp) []string {
+	var out []string
+	for _, op := range ops {
+		if op.kind != '-' {
+			out = append(out, op.text)
+		}
+	}
+	return out
+}
+
+func joinLines(lines []string) string {
+	return strings.Join(lines, "\n")
+}
+
+func TestDiffLinesReconstructsB(t *testing.T) {
+	cases := [][2][]string{
+		{nil, nil},
+		{[]string{"a"}, []string{"a"}},
+		{[]string{"a", "b", "c"}, []string{"a", "x", "c"}},
+		{[]string{"a", "b", "c"}, []string{"a", "b", "b", "c"}},
+		{[]string{"a", "b", "c"}, []string{"a", "c"}},
+		{[]string{}, []string{"a", "b"}},
+		{[]string{"a", "b"}, []string{}},
+		{[]string{"a", "b", "c", "d", "e"}, []string{"a", "z", "c", "d", "y"}},
+	}
+
+	for _, c := range cases {
+		a, b := c[0], c[1]
+		ops := diffLines(a, b)
+		got := reconstruct(ops)
+		if joinLines(got) != joinLines(b) {
+			t.Errorf("diffLines(%v, %v) reconstructs %v, want %v", a, b, got, b)
+		}
+	}
+}
+
+func TestDiffLinesNoSpuriousChanges(t *testing.T) {
+	a := []string{"1", "2", "3"}
+	ops := diffLines(a, a)
+	for _, op := range ops {
+		if op.kind != ' ' {
+			t.Fatalf("diffLines(a, a) produced a change op %+v, want all context", op)
+		}
+	}
+	if len(ops) != len(a) {
+		t.Fatalf("diffLines(a, a) produced %d ops, want %d", len(ops), len(a))
+	}
+}
+
+func TestFormatUnifiedDiffEmptyWhenUnchanged(t *testing.T) {
+	a := []string{"same", "same"}
+	ops := diffLines(a, a)
+	if patch := formatUnifiedDiff("f.txt", ops); patch != "" {
+		t.Errorf("formatUnifiedDiff with no changes = %q, want empty", patch)
+	}
+}
+
+func TestFormatUnifiedDiffSplitsDistantHunks(t *testing.T) {
+	var a, b []string
+	for i := 0; i < 20; i++ {
+		a = append(a, "line")
+		b = append(b, "line")
+	}
+	a[0] = "old-start"
+	b[0] = "new-start"
+	a[19] = "old-end"
+	b[19] = "new-end"
+
+	patch := formatUnifiedDiff("f.txt", diffLines(a, b))
+	if got := strings.Count(patch, "@@"); got != 4 {
+		t.Errorf("expected two hunks (4 '@@' markers), got %d in:\n%s", got, patch)
+	}
+	if !strings.Contains(patch, "-old-start") || !strings.Contains(patch, "+new-start") {
+		t.Errorf("patch missing start-of-file change:\n%s", patch)
+	}
+	if !strings.Contains(patch, "-old-end") || !strings.Contains(patch, "+new-end") {
+		t.Errorf("patch missing end-of-file change:\n%s", patch)
+	}
+}
+
+func TestFormatUnifiedDiffHeaders(t *testing.T) {
+	a := []string{"a", "b", "c"}
+	b := []string{"a", "x", "c"}
+	patch := formatUnifiedDiff("f.txt", diffLines(a, b))
+
+	wantLines := []string{
+		"--- a/f.txt",
+		"+++ b/f.txt",
+		"@@ -1,3 +1,3 @@",
+		" a",
+		"-b",
+		"+x",
+		" c",
+	}
+	want := strings.Join(wantLines, "\n") + "\n"
+	if patch != want {
+		t.Errorf("formatUnifiedDiff =\n%q\nwant\n%q", patch, want)
+	}
+}
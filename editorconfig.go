@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ecSection is a single [glob] section of a .editorconfig file, along with
+// the directory the file lives in (glob patterns are resolved relative to
+// it).
+type ecSection struct {
+	dir   string
+	glob  string
+	props map[string]string
+}
+
+// parseEditorConfigFile reads a single .editorconfig file, returning its
+// sections and whether it declared itself the topmost config (root = true).
+func parseEditorConfigFile(path string) (sections []ecSection, root bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	dir := filepath.Dir(path)
+	var cur *ecSection
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			if cur != nil {
+				sections = append(sections, *cur)
+			}
+			cur = &ecSection{dir: dir, glob: line[1 : len(line)-1], props: map[string]string{}}
+			continue
+		}
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(kv[0]))
+		val := strings.ToLower(strings.TrimSpace(kv[1]))
+		if key == "root" && cur == nil {
+			root = val == "true"
+			continue
+		}
+		if cur != nil {
+			cur.props[key] = val
+		}
+	}
+	if cur != nil {
+		sections = append(sections, *cur)
+	}
+	return
+}
+
+// editorConfigGlobToRegexp translates an .editorconfig glob pattern into a
+// regexp that matches a path relative to the directory the pattern's file
+// lives in.
+func editorConfigGlobToRegexp(glob string) *regexp.Regexp {
+	anchored := strings.Contains(glob, "/")
+	pattern := glob
+	if strings.HasPrefix(pattern, "/") {
+		pattern = pattern[1:]
+	}
+
+	var out strings.Builder
+	out.WriteString("^")
+	if !anchored {
+		out.WriteString("(.*/)?")
+	}
+
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch c {
+		case '*':
+			if i+1 < len(runes) && runes[i+1] == '*' {
+				out.WriteString(".*")
+				i++
+			} else {
+				out.WriteString("[^/]*")
+			}
+		case '?':
+			out.WriteString("[^/]")
+		case '.', '(', ')', '+', '|', '^', '$':
+			out.WriteString("\\")
+			out.WriteRune(c)
+		case '{':
+			// Brace alternation {a,b,c}
+			end := strings.IndexRune(string(runes[i:]), '}')
+			if end < 0 {
+				out.WriteString("\\{")
+				continue
+			}
+			group := string(runes[i+1 : i+end])
+			alts := strings.Split(group, ",")
+			out.WriteString("(")
+			out.WriteString(strings.Join(alts, "|"))
+			out.WriteString(")")
+			i += end
+		default:
+			out.WriteRune(c)
+		}
+	}
+	out.WriteString("$")
+	re, err := regexp.Compile(out.String())
+	if err != nil {
+		return regexp.MustCompile("$^") // matches nothing
+	}
+	return re
+}
+
+func (s ecSection) matches(path string) bool {
+	rel, err := filepath.Rel(s.dir, path)
+	if err != nil {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	return editorConfigGlobToRegexp(s.glob).MatchString(rel)
+}
+
+// loadEditorConfigProps walks upward from the directory containing filename,
+// merging properties from every matching section of every .editorconfig it
+// finds until it passes one marked root = true (or reaches the filesystem
+// root). Nearer files take precedence over farther ones; within a file,
+// later matching sections take precedence over earlier ones.
+func loadEditorConfigProps(filename string) map[string]string {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return nil
+	}
+
+	var configs [][]ecSection
+	dir := filepath.Dir(abs)
+	for {
+		sections, root := parseEditorConfigFile(filepath.Join(dir, ".editorconfig"))
+		if sections != nil {
+			configs = append(configs, sections)
+		}
+		if root {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	props := map[string]string{}
+	// Farthest config first, so nearer configs (applied last) win.
+	for i := len(configs) - 1; i >= 0; i-- {
+		for _, section := range configs[i] {
+			if !section.matches(abs) {
+				continue
+			}
+			for k, v := range section.props {
+				props[k] = v
+			}
+		}
+	}
+	return props
+}
+
+// applyEditorConfig overrides opts with any properties found in
+// .editorconfig files covering filename.
+func applyEditorConfig(filename string, opts CleanOptions) CleanOptions {
+	props := loadEditorConfigProps(filename)
+
+	if style, ok := props["indent_style"]; ok {
+		switch style {
+		case "tab":
+			opts.ContractTabs = true
+			opts.ExpandTabs = false
+		case "space":
+			opts.ExpandTabs = true
+			opts.ContractTabs = false
+		}
+	}
+	if size, ok := props["indent_size"]; ok {
+		if n, err := strconv.Atoi(size); err == nil {
+			opts.TabSize = n
+		}
+	}
+	if width, ok := props["tab_width"]; ok {
+		if n, err := strconv.Atoi(width); err == nil {
+			opts.TabSize = n
+		}
+	}
+	if nl, ok := props["insert_final_newline"]; ok {
+		switch nl {
+		case "true":
+			opts.AddTrailingNewline = true
+			opts.TrailingNewline = false
+		case "false":
+			opts.TrailingNewline = true
+			opts.AddTrailingNewline = false
+		}
+	}
+	if trim, ok := props["trim_trailing_whitespace"]; ok {
+		switch trim {
+		case "true":
+			opts.TrimTrailingWhitespace = true
+		case "false":
+			opts.TrimTrailingWhitespace = false
+		}
+	}
+	if eol, ok := props["end_of_line"]; ok {
+		switch eol {
+		case "lf", "crlf", "cr":
+			opts.EOL = eol
+		}
+	}
+
+	return opts
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, contents string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestApplyEditorConfigPrecedence checks that a nearer .editorconfig wins
+// key-by-key over a farther one, rather than one file's properties
+// replacing the other's wholesale.
+func TestApplyEditorConfigPrecedence(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, ".editorconfig"), `
+root = true
+
+[*]
+indent_style = space
+indent_size = 2
+`)
+	sub := filepath.Join(root, "sub")
+	writeFile(t, filepath.Join(sub, ".editorconfig"), `
+[*]
+indent_style = tab
+`)
+	target := filepath.Join(sub, "f.go")
+	writeFile(t, target, "x\n")
+
+	opts := applyEditorConfig(target, CleanOptions{})
+
+	if !opts.ContractTabs || opts.ExpandTabs {
+		t.Errorf("indent_style: nearer file's 'tab' should win, got ContractTabs=%v ExpandTabs=%v", opts.ContractTabs, opts.ExpandTabs)
+	}
+	if opts.TabSize != 2 {
+		t.Errorf("indent_size: farther file's value should survive since the nearer file doesn't override it, got %d, want 2", opts.TabSize)
+	}
+}
+
+// TestApplyEditorConfigRootStopsWalk checks that root = true stops the
+// upward directory walk: a .editorconfig above the root = true file must
+// not contribute properties.
+func TestApplyEditorConfigRootStopsWalk(t *testing.T) {
+	base := t.TempDir()
+	writeFile(t, filepath.Join(base, ".editorconfig"), `
+[*]
+indent_size = 8
+`)
+	sub := filepath.Join(base, "sub")
+	writeFile(t, filepath.Join(sub, ".editorconfig"), `
+root = true
+
+[*]
+indent_style = space
+`)
+	target := filepath.Join(sub, "f.go")
+	writeFile(t, target, "x\n")
+
+	opts := applyEditorConfig(target, CleanOptions{TabSize: 4})
+
+	if opts.TabSize != 4 {
+		t.Errorf("indent_size from above root = true leaked in: got TabSize=%d, want unchanged 4", opts.TabSize)
+	}
+	if !opts.ExpandTabs {
+		t.Errorf("indent_style = space from the root = true file should still apply")
+	}
+}
+
+// TestApplyEditorConfigTrimTrailingWhitespace and
+// TestApplyEditorConfigEndOfLine cover the two properties that were
+// silently ignored before being wired up.
+func TestApplyEditorConfigTrimTrailingWhitespace(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, ".editorconfig"), `
+root = true
+
+[*]
+trim_trailing_whitespace = false
+`)
+	target := filepath.Join(dir, "f.go")
+	writeFile(t, target, "x\n")
+
+	opts := applyEditorConfig(target, CleanOptions{TrimTrailingWhitespace: true})
+	if opts.TrimTrailingWhitespace {
+		t.Errorf("trim_trailing_whitespace = false should have been honored")
+	}
+}
+
+func TestApplyEditorConfigEndOfLine(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, ".editorconfig"), `
+root = true
+
+[*]
+end_of_line = crlf
+`)
+	target := filepath.Join(dir, "f.go")
+	writeFile(t, target, "x\n")
+
+	opts := applyEditorConfig(target, CleanOptions{EOL: "lf"})
+	if opts.EOL != "crlf" {
+		t.Errorf("end_of_line = crlf should have been honored, got EOL=%q", opts.EOL)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import "flag"
+
+var eolFlag = flag.String("eol", "lf", "Line ending to write: lf, crlf, cr, or keep (preserve the input's dominant ending)")
+
+const (
+	eolLF   = "\n"
+	eolCRLF = "\r\n"
+	eolCR   = "\r"
+)
+
+// eolTerminator maps an -eol flag value to its terminator string. ok is
+// false for "keep", which has no fixed terminator of its own.
+func eolTerminator(name string) (term string, ok bool) {
+	switch name {
+	case "lf":
+		return eolLF, true
+	case "crlf":
+		return eolCRLF, true
+	case "cr":
+		return eolCR, true
+	default:
+		return "", false
+	}
+}
+
+// splitLinesEOL splits contents into lines, recognizing \r\n, \n and \r as
+// line terminators. terms[i] is the terminator that followed lines[i] in the
+// original file, or "" if lines[i] was the last line and had none. dominant
+// is the most common terminator seen, for -eol=keep.
+func splitLinesEOL(contents []byte) (lines []string, terms []string, dominant string) {
+	s := string(contents)
+	counts := map[string]int{}
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\n':
+			lines = append(lines, s[start:i])
+			terms = append(terms, eolLF)
+			counts[eolLF]++
+			start = i + 1
+		case '\r':
+			if i+1 < len(s) && s[i+1] == '\n' {
+				lines = append(lines, s[start:i])
+				terms = append(terms, eolCRLF)
+				counts[eolCRLF]++
+				i++
+				start = i + 1
+			} else {
+				lines = append(lines, s[start:i])
+				terms = append(terms, eolCR)
+				counts[eolCR]++
+				start = i + 1
+			}
+		}
+	}
+	if start < len(s) {
+		lines = append(lines, s[start:])
+		terms = append(terms, "")
+	}
+
+	// Break ties by fixed precedence (lf > crlf > cr) rather than ranging
+	// counts directly, since map iteration order is random and would make
+	// dominant non-deterministic on tied input.
+	dominant = eolLF
+	best := 0
+	for _, term := range []string{eolLF, eolCRLF, eolCR} {
+		if counts[term] > best {
+			best = counts[term]
+			dominant = term
+		}
+	}
+	return
+}
+
+// resolveEOL picks the terminator to write, resolving "keep" against the
+// file's own dominant terminator.
+func resolveEOL(opt, dominant string) string {
+	if term, ok := eolTerminator(opt); ok {
+		return term
+	}
+	return dominant
+}
+
+// countEOLChanges counts how many original terminators differ from target,
+// ignoring lines that had no terminator at all.
+func countEOLChanges(terms []string, target string) int {
+	n := 0
+	for _, t := range terms {
+		if t != "" && t != target {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,38 @@
+package main
+
+import "testing"
+
+func TestSplitLinesEOLDetectsTerminators(t *testing.T) {
+	lines, terms, dominant := splitLinesEOL([]byte("a\nb\r\nc\rd"))
+	wantLines := []string{"a", "b", "c", "d"}
+	wantTerms := []string{eolLF, eolCRLF, eolCR, ""}
+	if len(lines) != len(wantLines) {
+		t.Fatalf("lines = %v, want %v", lines, wantLines)
+	}
+	for i := range wantLines {
+		if lines[i] != wantLines[i] || terms[i] != wantTerms[i] {
+			t.Errorf("line %d = %q/%q, want %q/%q", i, lines[i], terms[i], wantLines[i], wantTerms[i])
+		}
+	}
+	if dominant != eolLF {
+		t.Errorf("dominant = %q, want %q (each terminator appears once, lf should win the tie)", dominant, eolLF)
+	}
+}
+
+func TestSplitLinesEOLTieBreakIsDeterministic(t *testing.T) {
+	contents := []byte("a\nb\r\n")
+	_, _, want := splitLinesEOL(contents)
+	for i := 0; i < 500; i++ {
+		_, _, got := splitLinesEOL(contents)
+		if got != want {
+			t.Fatalf("dominant changed across calls: got %q, want %q", got, want)
+		}
+	}
+}
+
+func TestSplitLinesEOLMajorityWins(t *testing.T) {
+	_, _, dominant := splitLinesEOL([]byte("a\r\nb\r\nc\n"))
+	if dominant != eolCRLF {
+		t.Errorf("dominant = %q, want %q", dominant, eolCRLF)
+	}
+}
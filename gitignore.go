@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// gitignoreRule is a single pattern line from a .gitignore file, resolved
+// against the directory that file lives in.
+type gitignoreRule struct {
+	dir     string
+	negate  bool
+	dirOnly bool
+	re      *regexp.Regexp
+}
+
+// loadGitignoreRules reads the .gitignore file in dir, if any, and returns
+// its rules in file order (order matters: later rules override earlier
+// ones when both match).
+func loadGitignoreRules(dir string) []gitignoreRule {
+	f, err := os.Open(filepath.Join(dir, ".gitignore"))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var rules []gitignoreRule
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " ")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		negate := false
+		if strings.HasPrefix(line, "!") {
+			negate = true
+			line = line[1:]
+		}
+		dirOnly := strings.HasSuffix(line, "/")
+		if dirOnly {
+			line = line[:len(line)-1]
+		}
+		rules = append(rules, gitignoreRule{
+			dir:     dir,
+			negate:  negate,
+			dirOnly: dirOnly,
+			re:      gitignoreGlobToRegexp(line),
+		})
+	}
+	return rules
+}
+
+// gitignoreGlobToRegexp translates a single gitignore pattern into a regexp
+// matched against a path relative to the .gitignore's directory.
+func gitignoreGlobToRegexp(pattern string) *regexp.Regexp {
+	anchored := strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	if strings.HasPrefix(pattern, "/") {
+		pattern = pattern[1:]
+	}
+
+	var out strings.Builder
+	out.WriteString("^")
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch c {
+		case '*':
+			if i+1 < len(runes) && runes[i+1] == '*' {
+				if i+2 < len(runes) && runes[i+2] == '/' {
+					// "**/" matches zero or more whole path segments,
+					// including the separator: keep it as one unit so a
+					// bare filename can't glue onto what follows without a
+					// "/" between them.
+					out.WriteString("(?:.*/)?")
+					i += 2
+				} else {
+					out.WriteString(".*")
+					i++
+				}
+			} else {
+				out.WriteString("[^/]*")
+			}
+		case '?':
+			out.WriteString("[^/]")
+		case '.', '(', ')', '+', '|', '^', '$':
+			out.WriteString("\\")
+			out.WriteRune(c)
+		default:
+			out.WriteRune(c)
+		}
+	}
+	out.WriteString("(/.*)?$")
+	re, err := regexp.Compile(out.String())
+	if err != nil {
+		return regexp.MustCompile("$^")
+	}
+	if !anchored {
+		// Unanchored patterns match at any depth, so allow an arbitrary
+		// leading path segment.
+		re = regexp.MustCompile("^(.*/)?" + out.String()[1:])
+	}
+	return re
+}
+
+// ignored reports whether path (an absolute path) is ignored by the given
+// accumulated rule set, which must be ordered from the outermost ancestor
+// .gitignore down to the innermost.
+func ignored(path string, isDir bool, rules []gitignoreRule) bool {
+	result := false
+	for _, r := range rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		rel, err := filepath.Rel(r.dir, path)
+		if err != nil {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		if r.re.MatchString(rel) {
+			result = !r.negate
+		}
+	}
+	return result
+}
@@ -0,0 +1,63 @@
+package main
+
+import "testing"
+
+func TestGitignoreGlobToRegexp(t *testing.T) {
+	cases := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"*.o", "foo.o", true},
+		{"*.o", "sub/foo.o", true},
+		{"*.o", "foo.oh", false},
+		{"/build", "build", true},
+		{"/build", "sub/build", false},
+		{"foo/**/bar", "foo/bar", true},
+		{"foo/**/bar", "foo/a/bar", true},
+		{"foo/**/bar", "foo/a/b/bar", true},
+		// Regression: a bare "xyzbar" must not match "**/bar" just because
+		// it ends in "bar" with no path separator before it.
+		{"foo/**/bar", "foo/xyzbar", false},
+		{"foo/**/bar", "foo/a/xyzbar", false},
+	}
+
+	for _, c := range cases {
+		re := gitignoreGlobToRegexp(c.pattern)
+		got := re.MatchString(c.path)
+		if got != c.want {
+			t.Errorf("gitignoreGlobToRegexp(%q).MatchString(%q) = %v, want %v (regexp %s)",
+				c.pattern, c.path, got, c.want, re.String())
+		}
+	}
+}
+
+func TestIgnored(t *testing.T) {
+	rules := []gitignoreRule{
+		{dir: "/root", re: gitignoreGlobToRegexp("foo/**/bar")},
+	}
+
+	if ignored("/root/foo/xyzbar", false, rules) {
+		t.Errorf("ignored(/root/foo/xyzbar) = true, want false: a literal file named xyzbar must not match foo/**/bar")
+	}
+	if !ignored("/root/foo/bar", false, rules) {
+		t.Errorf("ignored(/root/foo/bar) = false, want true")
+	}
+	if !ignored("/root/foo/a/bar", false, rules) {
+		t.Errorf("ignored(/root/foo/a/bar) = false, want true")
+	}
+}
+
+func TestIgnoredNegation(t *testing.T) {
+	rules := []gitignoreRule{
+		{dir: "/root", re: gitignoreGlobToRegexp("*.o")},
+		{dir: "/root", re: gitignoreGlobToRegexp("keep.o"), negate: true},
+	}
+
+	if !ignored("/root/drop.o", false, rules) {
+		t.Errorf("expected drop.o to be ignored")
+	}
+	if ignored("/root/keep.o", false, rules) {
+		t.Errorf("expected keep.o to be un-ignored by the negated rule")
+	}
+}
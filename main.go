@@ -5,57 +5,90 @@ import "os"
 import "io/ioutil"
 import "strings"
 import "flag"
+import "time"
+
+import "github.com/soulplant/clean/safewrite"
 
 var expandTabsFlag = flag.Bool("e", false, "Expand tabs into spaces")
 var contractTabsFlag = flag.Bool("c", false, "Contract spaces into tabs")
 var tabSize = flag.Int("ts", 4, "Size of tabs")
 var trailingNewlineFlag = flag.Bool("t", false, "Remove trailing newlines")
 var addTrailingNewlineFlag = flag.Bool("at", false, "Add trailing newline")
+var editorConfigFlag = flag.Bool("editorconfig", false, "Honor .editorconfig files, overriding flags per file")
+var dryRunFlag = flag.Bool("n", false, "Show what would change, as a unified diff, without writing anything")
+var dryRunLongFlag = flag.Bool("dry-run", false, "Alias for -n")
 var helpFlag = flag.Bool("h", false, "Display usage")
+var lockTimeoutFlag = flag.Duration("lock-timeout", 5*time.Second, "How long to wait for the advisory lock on a file before giving up")
 
-func isText(filename string) bool {
-	f, _ := os.Open(filename, 0, 0)
-	contents := make([]uint8, 1024)
-	n, _ := f.Read(contents)
+func isDryRun() bool {
+	return *dryRunFlag || *dryRunLongFlag
+}
 
-	for i := 0; i < 1024 && i < n; i++ {
-		if contents[i] > 0x7e || contents[i] < 0x09 {
-			return false
-		}
+// fixVerb returns the verb logModifications should report under, which
+// differs in dry-run mode since nothing is actually written.
+func fixVerb() string {
+	if isDryRun() {
+		return "Would fix"
 	}
-	return true
+	return "Fixed"
+}
+
+// CleanOptions holds the set of knobs that control how a single file is
+// cleaned. It starts out as a copy of the global flags, and may then be
+// overridden on a per-file basis (e.g. by -editorconfig).
+type CleanOptions struct {
+	ExpandTabs             bool
+	ContractTabs           bool
+	TabSize                int
+	TrailingNewline        bool
+	AddTrailingNewline     bool
+	EOL                    string // "lf", "crlf", "cr" or "keep"
+	TrimTrailingWhitespace bool
 }
 
-func expandTabs(str string) (result string) {
+// optionsFromFlags builds a CleanOptions from the global command-line flags.
+func optionsFromFlags() CleanOptions {
+	return CleanOptions{
+		ExpandTabs:             *expandTabsFlag,
+		ContractTabs:           *contractTabsFlag,
+		TabSize:                *tabSize,
+		TrailingNewline:        *trailingNewlineFlag,
+		TrimTrailingWhitespace: true,
+		AddTrailingNewline:     *addTrailingNewlineFlag,
+		EOL:                    *eolFlag,
+	}
+}
+
+func expandTabs(str string, opts CleanOptions) (result string) {
 	start := true
 	for i := range str {
 		if start && str[i] == '\t' {
-			result += strings.Repeat(" ", *tabSize)
+			result += strings.Repeat(" ", opts.TabSize)
 		} else {
 			start = false
-			result += str[i:i+1]
+			result += str[i : i+1]
 		}
 	}
 	return
 }
 
-func chompTab(str string) string {
-	if len(str) < *tabSize {
+func chompTab(str string, opts CleanOptions) string {
+	if len(str) < opts.TabSize {
 		return str
 	}
-	tab := strings.Repeat(" ", *tabSize)
+	tab := strings.Repeat(" ", opts.TabSize)
 
 	if strings.HasPrefix(str, tab) {
-		return str[*tabSize:]
+		return str[opts.TabSize:]
 	}
 	return str
 }
 
-func contractTabs(str string) string {
+func contractTabs(str string, opts CleanOptions) string {
 	tabsChomped := 0
 	for {
 		old_len := len(str)
-		str = chompTab(str)
+		str = chompTab(str, opts)
 		if len(str) != old_len {
 			tabsChomped++
 		} else {
@@ -65,7 +98,7 @@ func contractTabs(str string) string {
 	return strings.Repeat("\t", tabsChomped) + str
 }
 
-func removeTrailingNewlines(lines []string) []string {
+func removeTrailingNewlines(lines []string, opts CleanOptions) []string {
 	empties := 0
 	for i := len(lines) - 1; i >= 0; i-- {
 		if lines[i] == "" {
@@ -75,61 +108,124 @@ func removeTrailingNewlines(lines []string) []string {
 		}
 	}
 
-	return lines[:len(lines) - empties]
+	return lines[:len(lines)-empties]
 }
 
-func cleanFile(filename string) (trimmed, tabs int) {
-	contents, _ := ioutil.ReadFile(filename)
-
-	if len(contents) == 0 {
-		return
-	}
-
-	// Chomp the last newline, because split creates an extra blank after it.
-	if contents[len(contents)-1] == '\n' {
-		contents = contents[:len(contents)-1]
-	}
-
-
-	lines := strings.Split(string(contents), "\n", -1)
+// cleanLines computes the cleaned form of lines according to opts, without
+// touching the filesystem or printing anything: it's the pure core shared
+// by cleanFile and the dry-run / check modes, which each decide for
+// themselves whether addedNewline/removedNewlines are worth reporting.
+func cleanLines(lines []string, opts CleanOptions) (newLines []string, trimmed, tabs int, addedNewline, removedNewlines bool) {
+	lines = append([]string(nil), lines...)
 
-	if *addTrailingNewlineFlag && lines[len(lines)-1] != "" {
+	if opts.AddTrailingNewline && lines[len(lines)-1] != "" {
 		lines = append(lines, "")
-		fmt.Printf("Added trailing newline.\n")
+		addedNewline = true
 	}
 
-	if *trailingNewlineFlag {
+	if opts.TrailingNewline {
 		l := len(lines)
-		lines = removeTrailingNewlines(lines)
-		if len(lines) != l {
-			fmt.Printf("Removed trailing newlines.\n")
-		}
+		lines = removeTrailingNewlines(lines, opts)
+		removedNewlines = len(lines) != l
 	}
 
-	output := ""
+	newLines = make([]string, 0, len(lines))
 	for _, str := range lines {
-		ts := strings.TrimRight(str, " \t")
+		ts := str
+		if opts.TrimTrailingWhitespace {
+			ts = strings.TrimRight(str, " \t")
+		}
 		if len(ts) < len(str) {
 			trimmed++
 		}
 		lts := len(ts)
 		switch {
-			case *contractTabsFlag:
-				ts = contractTabs(ts)
-				if len(ts) != lts {
-					tabs++
-				}
-				break
-			case *expandTabsFlag:
-				ts = expandTabs(ts)
-				if len(ts) != lts {
-					tabs++
-				}
-				break
-		}
-		output += ts + "\n"
-	}
-	ioutil.WriteFile(filename, []uint8(output), 0666)
+		case opts.ContractTabs:
+			ts = contractTabs(ts, opts)
+			if len(ts) != lts {
+				tabs++
+			}
+			break
+		case opts.ExpandTabs:
+			ts = expandTabs(ts, opts)
+			if len(ts) != lts {
+				tabs++
+			}
+			break
+		}
+		newLines = append(newLines, ts)
+	}
+	return
+}
+
+// cleanFile cleans filename in place according to opts. The read, clean and
+// write happen atomically with respect to other `clean` invocations: see
+// safewrite.TransformFile.
+func cleanFile(filename string, opts CleanOptions) (trimmed, tabs, eols int) {
+	err := safewrite.TransformFile(filename, *lockTimeoutFlag, func(contents []byte) ([]byte, error) {
+		if len(contents) == 0 {
+			return contents, nil
+		}
+		body, hadBOM := stripBOM(contents)
+
+		lines, terms, dominant := splitLinesEOL(body)
+		var newLines []string
+		var addedNewline, removedNewlines bool
+		newLines, trimmed, tabs, addedNewline, removedNewlines = cleanLines(lines, opts)
+		// Built as one string and printed in a single call so concurrent
+		// -r workers can't interleave it mid-line (see logModifications).
+		status := ""
+		if addedNewline {
+			status += "Added trailing newline.\n"
+		}
+		if removedNewlines {
+			status += "Removed trailing newlines.\n"
+		}
+		if status != "" {
+			fmt.Print(status)
+		}
+
+		target := resolveEOL(opts.EOL, dominant)
+		eols = countEOLChanges(terms, target)
+
+		output := ""
+		if hadBOM {
+			output = string(utf8BOM)
+		}
+		for _, str := range newLines {
+			output += str + target
+		}
+		return []byte(output), nil
+	})
+	if err != nil {
+		fmt.Printf("Couldn't clean %s: %s\n", filename, err)
+	}
+	return
+}
+
+// diffFile computes what cleanFile would do to filename but, instead of
+// writing it, prints a unified diff to stdout.
+func diffFile(filename string, opts CleanOptions) (trimmed, tabs, eols int) {
+	contents, _ := ioutil.ReadFile(filename)
+	if len(contents) == 0 {
+		return
+	}
+	contents, _ = stripBOM(contents)
+
+	lines, terms, dominant := splitLinesEOL(contents)
+	newLines, trimmed, tabs, _, _ := cleanLines(lines, opts)
+	eols = countEOLChanges(terms, resolveEOL(opts.EOL, dominant))
+
+	if trimmed == 0 && tabs == 0 && eols == 0 && len(lines) == len(newLines) {
+		return
+	}
+
+	if patch := formatUnifiedDiff(filename, diffLines(lines, newLines)); patch != "" {
+		fmt.Print(patch)
+	}
+	if eols > 0 {
+		fmt.Printf("%s: would convert %d line %s\n", filename, eols, pluralize("ending", eols))
+	}
 	return
 }
 
@@ -145,27 +241,43 @@ func isRegular(fn string) bool {
 	if err != nil {
 		return false
 	}
-	return s.IsRegular()
+	return s.Mode().IsRegular()
 }
 
-func logModifications(fn string, trims, tabs int) {
-	fmt.Printf("Fixed %d %s", trims, pluralize("line", trims))
+// logModifications builds the whole message before printing it, rather
+// than calling Printf piece by piece, so that concurrent callers (e.g. the
+// -r worker pool) can't have their output interleaved line to line. verb is
+// "Fixed" for a real run or "Would fix" for -n/-dry-run, which doesn't
+// write anything.
+func logModifications(fn string, trims, tabs, eols int, verb string) {
+	msg := fmt.Sprintf("%s %d %s", verb, trims, pluralize("line", trims))
 	if tabs > 0 {
-		fmt.Printf(" and %d %s", tabs, pluralize("tab", tabs))
+		msg += fmt.Sprintf(" and %d %s", tabs, pluralize("tab", tabs))
+	}
+	if eols > 0 {
+		msg += fmt.Sprintf(" and converted %d line %s", eols, pluralize("ending", eols))
 	}
 	if fn != "" {
-		fmt.Printf(" in %s", fn)
+		msg += fmt.Sprintf(" in %s", fn)
 	}
-	fmt.Println()
+	fmt.Println(msg)
 }
 
-func processFile(fn string) (trims, tabs int) {
+func processFile(fn string) (trims, tabs, eols int) {
 	if !isRegular(fn) {
 		fmt.Printf("Couldn't clean %s\n", fn)
 		return
 	}
-	if isText(fn) {
-		trims, tabs = cleanFile(fn)
+	if shouldTreatAsText(fn) {
+		opts := optionsFromFlags()
+		if *editorConfigFlag {
+			opts = applyEditorConfig(fn, opts)
+		}
+		if isDryRun() {
+			trims, tabs, eols = diffFile(fn, opts)
+		} else {
+			trims, tabs, eols = cleanFile(fn, opts)
+		}
 	} else {
 		fmt.Printf("Didn't clean binary file %s\n", fn)
 	}
@@ -182,18 +294,44 @@ func main() {
 		fmt.Println("Can't contract and expand tabs.")
 		os.Exit(1)
 	}
+	if _, ok := eolTerminator(*eolFlag); !ok && *eolFlag != "keep" {
+		fmt.Printf("Unknown -eol value %q; want lf, crlf, cr or keep.\n", *eolFlag)
+		os.Exit(1)
+	}
 
-	trims, tabs := 0, 0
 	if len(flag.Args()) == 0 {
 		fmt.Println("No files to work on.")
 		os.Exit(0)
 	}
-	for _, fn := range flag.Args() {
-		trs, tas := processFile(fn)
-		logModifications(fn, trs, tas)
-		trims += trs
-		tabs += tas
+
+	var files []string
+	if *recurseFlag {
+		includes := splitGlobs(*includeFlag)
+		excludes := splitGlobs(*excludeFlag)
+		for _, root := range flag.Args() {
+			files = append(files, collectFiles(root, includes, excludes)...)
+		}
+	} else {
+		files = flag.Args()
+	}
+
+	if *checkFlag {
+		runCheck(files)
+		return
+	}
+
+	trims, tabs, eols := 0, 0, 0
+	if *recurseFlag {
+		trims, tabs, eols = processFilesParallel(files)
+	} else {
+		for _, fn := range files {
+			trs, tas, els := processFile(fn)
+			logModifications(fn, trs, tas, els, fixVerb())
+			trims += trs
+			tabs += tas
+			eols += els
+		}
 	}
 
-	logModifications("", trims, tabs)
+	logModifications("", trims, tabs, eols, fixVerb())
 }
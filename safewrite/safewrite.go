@@ -0,0 +1,97 @@
+// Package safewrite provides a way to rewrite a file's contents without
+// the risk of truncating or corrupting it if two processes touch it at
+// once: writes go to a sibling temp file that's renamed into place, with
+// an advisory flock held across the whole read-modify-write.
+package safewrite
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// TransformFile opens path, takes an advisory exclusive flock on it, reads
+// its current contents and passes them to transform. If transform returns
+// contents different from what was read, the result is written back
+// atomically (via a temp file in the same directory, renamed into place)
+// before the lock is released. The original file's mode and ownership are
+// preserved on the replacement. Waiting for the lock gives up after
+// timeout.
+func TransformFile(path string, timeout time.Duration, transform func([]byte) ([]byte, error)) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := lock(f, timeout); err != nil {
+		return err
+	}
+	defer syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+
+	contents, err := ioutil.ReadAll(f)
+	if err != nil {
+		return err
+	}
+
+	newContents, err := transform(contents)
+	if err != nil {
+		return err
+	}
+	if string(newContents) == string(contents) {
+		return nil
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return rename(path, newContents, info)
+}
+
+func rename(path string, contents []byte, info os.FileInfo) error {
+	dir := filepath.Dir(path)
+	tmp, err := ioutil.TempFile(dir, "."+filepath.Base(path)+".")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+
+	if _, err := tmp.Write(contents); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+
+	os.Chmod(tmpName, info.Mode())
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		os.Chown(tmpName, int(st.Uid), int(st.Gid))
+	}
+
+	if err := os.Rename(tmpName, path); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	return nil
+}
+
+func lock(f *os.File, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("safewrite: timed out waiting for a lock on %s", f.Name())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
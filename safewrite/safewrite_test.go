@@ -0,0 +1,93 @@
+package safewrite
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTransformFileRewritesContents(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "f.txt")
+	if err := os.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := TransformFile(path, time.Second, func(contents []byte) ([]byte, error) {
+		return []byte(string(contents) + " world"), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "hello world" {
+		t.Errorf("contents = %q, want %q", got, "hello world")
+	}
+}
+
+func TestTransformFileNoOpWhenUnchanged(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "f.txt")
+	if err := os.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = TransformFile(path, time.Second, func(contents []byte) ([]byte, error) {
+		return contents, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	after, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before.ModTime() != after.ModTime() {
+		t.Errorf("file was rewritten even though transform returned identical contents")
+	}
+}
+
+func TestTransformFilePreservesMode(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "f.txt")
+	if err := os.WriteFile(path, []byte("hello"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	err := TransformFile(path, time.Second, func(contents []byte) ([]byte, error) {
+		return []byte("changed"), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestTransformFileMissingFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "nope.txt")
+
+	err := TransformFile(path, time.Second, func(contents []byte) ([]byte, error) {
+		return contents, nil
+	})
+	if err == nil {
+		t.Error("expected an error for a missing file, got nil")
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"unicode/utf8"
+)
+
+var forceTextFlag = flag.Bool("force-text", false, "Treat every file as text, skipping binary detection")
+var forceBinaryFlag = flag.Bool("force-binary", false, "Treat every file as binary, skipping it unconditionally")
+
+// utf8BOM is the three-byte UTF-8 byte order mark.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+const sniffLen = 8192
+
+// isText sniffs up to sniffLen bytes of filename and reports whether it
+// looks like text, the way git and file(1) do: a NUL byte anywhere in the
+// sample means binary, otherwise a valid UTF-8 stream (BOM allowed) or a
+// mostly-printable Latin-1 stream both count as text.
+func isText(filename string) bool {
+	f, err := os.Open(filename)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	buf := make([]byte, sniffLen)
+	n, _ := f.Read(buf)
+	return looksLikeText(buf[:n])
+}
+
+func looksLikeText(data []byte) bool {
+	if bytes.IndexByte(data, 0) >= 0 {
+		return false
+	}
+	if len(data) == 0 {
+		return true
+	}
+
+	data = bytes.TrimPrefix(data, utf8BOM)
+	if validUTF8(data) {
+		return true
+	}
+
+	controls := 0
+	for _, b := range data {
+		switch b {
+		case '\t', '\n', '\r', '\f':
+			continue
+		}
+		if b < 0x20 || b == 0x7f {
+			controls++
+		}
+	}
+	return float64(controls)/float64(len(data)) < 0.30
+}
+
+// validUTF8 reports whether data is a well-formed UTF-8 byte stream.
+func validUTF8(data []byte) bool {
+	for len(data) > 0 {
+		r, size := utf8.DecodeRune(data)
+		if r == utf8.RuneError && size <= 1 {
+			return false
+		}
+		data = data[size:]
+	}
+	return true
+}
+
+// shouldTreatAsText applies the -force-text/-force-binary overrides on top
+// of isText's sniffing.
+func shouldTreatAsText(filename string) bool {
+	if *forceBinaryFlag {
+		return false
+	}
+	if *forceTextFlag {
+		return true
+	}
+	return isText(filename)
+}
+
+// stripBOM removes a leading UTF-8 byte order mark from contents, if
+// present, reporting whether it found one so callers can restore it.
+func stripBOM(contents []byte) (rest []byte, hadBOM bool) {
+	if bytes.HasPrefix(contents, utf8BOM) {
+		return contents[len(utf8BOM):], true
+	}
+	return contents, false
+}
@@ -0,0 +1,39 @@
+package main
+
+import "testing"
+
+func TestLooksLikeText(t *testing.T) {
+	cases := []struct {
+		name string
+		data []byte
+		want bool
+	}{
+		{"empty", []byte{}, true},
+		{"plain ascii", []byte("hello\nworld\n"), true},
+		{"nul byte", []byte("hello\x00world"), false},
+		{"valid utf8", []byte("héllo wörld\n"), true},
+		{"utf8 with BOM", append(append([]byte{}, utf8BOM...), []byte("hello\n")...), true},
+		{"mostly control bytes, invalid utf8", []byte{0x01, 0x02, 0x03, 0x04, 0x80}, false},
+		{"one control byte among mostly-printable non-utf8 text", append([]byte("some long line of regular text h\x80re"), 0x01), true},
+	}
+
+	for _, c := range cases {
+		if got := looksLikeText(c.data); got != c.want {
+			t.Errorf("looksLikeText(%s) = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestStripBOM(t *testing.T) {
+	withBOM := append(append([]byte{}, utf8BOM...), []byte("hi")...)
+	rest, hadBOM := stripBOM(withBOM)
+	if !hadBOM || string(rest) != "hi" {
+		t.Errorf("stripBOM(with BOM) = %q, %v, want %q, true", rest, hadBOM, "hi")
+	}
+
+	noBOM := []byte("hi")
+	rest, hadBOM = stripBOM(noBOM)
+	if hadBOM || string(rest) != "hi" {
+		t.Errorf("stripBOM(without BOM) = %q, %v, want %q, false", rest, hadBOM, "hi")
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+var recurseFlag = flag.Bool("r", false, "Recurse into directories, honoring .gitignore")
+var includeFlag = flag.String("include", "", "Comma-separated globs; only matching files are processed")
+var excludeFlag = flag.String("exclude", "", "Comma-separated globs; matching files are skipped")
+var workersFlag = flag.Int("j", runtime.NumCPU(), "Number of files to clean in parallel in -r mode")
+
+func splitGlobs(s string) []string {
+	if s == "" {
+		return nil
+	}
+	parts := strings.Split(s, ",")
+	globs := make([]string, 0, len(parts))
+	for _, p := range parts {
+		p = strings.TrimSpace(p)
+		if p != "" {
+			globs = append(globs, p)
+		}
+	}
+	return globs
+}
+
+func matchesAny(globs []string, name string) bool {
+	for _, g := range globs {
+		if ok, _ := filepath.Match(g, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// collectFiles walks root (a file or directory) and returns every
+// non-directory entry under it that survives .gitignore, -include and -exclude filtering.
+// A bare file argument is returned as-is, un-filtered, matching the
+// non-recursive behaviour.
+func collectFiles(root string, includes, excludes []string) []string {
+	info, err := os.Stat(root)
+	if err != nil {
+		return []string{root}
+	}
+	if !info.IsDir() {
+		return []string{root}
+	}
+
+	var files []string
+	walkDir(root, nil, includes, excludes, &files)
+	return files
+}
+
+func walkDir(dir string, rules []gitignoreRule, includes, excludes []string, out *[]string) {
+	rules = append(rules, loadGitignoreRules(dir)...)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if entry.Name() == ".git" {
+			continue
+		}
+		if ignored(path, entry.IsDir(), rules) {
+			continue
+		}
+		if entry.IsDir() {
+			walkDir(path, rules, includes, excludes, out)
+			continue
+		}
+		if len(includes) > 0 && !matchesAny(includes, entry.Name()) {
+			continue
+		}
+		if matchesAny(excludes, entry.Name()) {
+			continue
+		}
+		*out = append(*out, path)
+	}
+}
+
+// processFilesParallel cleans files using a bounded pool of workers,
+// aggregating each file's (trims, tabs, eols) counts through a channel.
+func processFilesParallel(files []string) (trims, tabs, eols int) {
+	type result struct{ trims, tabs, eols int }
+
+	jobs := make(chan string)
+	results := make(chan result)
+
+	workers := *workersFlag
+	if workers < 1 {
+		workers = 1
+	}
+	for w := 0; w < workers; w++ {
+		go func() {
+			for fn := range jobs {
+				trs, tas, els := processFile(fn)
+				logModifications(fn, trs, tas, els, fixVerb())
+				results <- result{trs, tas, els}
+			}
+		}()
+	}
+
+	go func() {
+		for _, fn := range files {
+			jobs <- fn
+		}
+		close(jobs)
+	}()
+
+	for range files {
+		r := <-results
+		trims += r.trims
+		tabs += r.tabs
+		eols += r.eols
+	}
+	return
+}